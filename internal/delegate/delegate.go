@@ -0,0 +1,160 @@
+// Package delegate shares the optional-interface delegation logic used by
+// the proxy and middleware packages' buffering http.ResponseWriter
+// wrappers: both need to expose exactly the combination of http.Flusher,
+// http.CloseNotifier, http.Hijacker and io.ReaderFrom that their
+// destination writer supports, so a type assertion against the result
+// (e.g. `_, ok := w.(http.Hijacker)`) stays truthful instead of always
+// succeeding.
+package delegate
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Capabilities holds the optional streaming hooks a buffering
+// http.ResponseWriter wrapper exposes once it commits. Each field is the
+// wrapper's own implementation (e.g. one that commits buffered state
+// before delegating to the real destination writer); WithCapabilities
+// decides which of them to attach based on what dst itself supports.
+type Capabilities struct {
+	Flush       func()
+	CloseNotify func() <-chan bool
+	Hijack      func() (net.Conn, *bufio.ReadWriter, error)
+	ReadFrom    func(io.Reader) (int64, error)
+}
+
+// WithCapabilities wraps core in whichever combination of http.Flusher,
+// http.CloseNotifier, http.Hijacker and io.ReaderFrom matches what dst
+// itself implements, in the style of prometheus/client_golang/promhttp's
+// delegator: a handler given the result observes core's real capabilities
+// rather than a false positive that only surfaces as an error once the
+// optional method is actually called.
+func WithCapabilities(core http.ResponseWriter, dst http.ResponseWriter, caps Capabilities) http.ResponseWriter {
+	_, f := dst.(http.Flusher)
+	_, cn := dst.(http.CloseNotifier) // nolint:staticcheck
+	_, h := dst.(http.Hijacker)
+	_, rf := dst.(io.ReaderFrom)
+
+	switch {
+	case f && cn && h && rf:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.CloseNotifier
+			http.Hijacker
+			io.ReaderFrom
+		}{core, flusherWriter{caps.Flush}, closeNotifierWriter{caps.CloseNotify}, hijackerWriter{caps.Hijack}, readerFromWriter{caps.ReadFrom}}
+	case f && cn && h:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.CloseNotifier
+			http.Hijacker
+		}{core, flusherWriter{caps.Flush}, closeNotifierWriter{caps.CloseNotify}, hijackerWriter{caps.Hijack}}
+	case f && cn && rf:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.CloseNotifier
+			io.ReaderFrom
+		}{core, flusherWriter{caps.Flush}, closeNotifierWriter{caps.CloseNotify}, readerFromWriter{caps.ReadFrom}}
+	case f && h && rf:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{core, flusherWriter{caps.Flush}, hijackerWriter{caps.Hijack}, readerFromWriter{caps.ReadFrom}}
+	case cn && h && rf:
+		return struct {
+			http.ResponseWriter
+			http.CloseNotifier
+			http.Hijacker
+			io.ReaderFrom
+		}{core, closeNotifierWriter{caps.CloseNotify}, hijackerWriter{caps.Hijack}, readerFromWriter{caps.ReadFrom}}
+	case f && cn:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.CloseNotifier
+		}{core, flusherWriter{caps.Flush}, closeNotifierWriter{caps.CloseNotify}}
+	case f && h:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+		}{core, flusherWriter{caps.Flush}, hijackerWriter{caps.Hijack}}
+	case f && rf:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{core, flusherWriter{caps.Flush}, readerFromWriter{caps.ReadFrom}}
+	case cn && h:
+		return struct {
+			http.ResponseWriter
+			http.CloseNotifier
+			http.Hijacker
+		}{core, closeNotifierWriter{caps.CloseNotify}, hijackerWriter{caps.Hijack}}
+	case cn && rf:
+		return struct {
+			http.ResponseWriter
+			http.CloseNotifier
+			io.ReaderFrom
+		}{core, closeNotifierWriter{caps.CloseNotify}, readerFromWriter{caps.ReadFrom}}
+	case h && rf:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{core, hijackerWriter{caps.Hijack}, readerFromWriter{caps.ReadFrom}}
+	case f:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+		}{core, flusherWriter{caps.Flush}}
+	case cn:
+		return struct {
+			http.ResponseWriter
+			http.CloseNotifier
+		}{core, closeNotifierWriter{caps.CloseNotify}}
+	case h:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+		}{core, hijackerWriter{caps.Hijack}}
+	case rf:
+		return struct {
+			http.ResponseWriter
+			io.ReaderFrom
+		}{core, readerFromWriter{caps.ReadFrom}}
+	default:
+		return core
+	}
+}
+
+// The types below each promote exactly one optional interface, backed by
+// the corresponding Capabilities func, on top of whatever core
+// http.ResponseWriter WithCapabilities combines them with.
+type flusherWriter struct{ flush func() }
+
+func (w flusherWriter) Flush() { w.flush() }
+
+type closeNotifierWriter struct{ closeNotify func() <-chan bool }
+
+func (w closeNotifierWriter) CloseNotify() <-chan bool { return w.closeNotify() }
+
+type hijackerWriter struct {
+	hijack func() (net.Conn, *bufio.ReadWriter, error)
+}
+
+func (w hijackerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type readerFromWriter struct {
+	readFrom func(io.Reader) (int64, error)
+}
+
+func (w readerFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
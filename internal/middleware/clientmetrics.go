@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/0xProject/rpc-gateway/internal/prometheusmetrics"
+)
+
+// ClientMetrics records the active-clients gauge and per-client request
+// counter for every request before handing off to next. Clients are
+// identified by the apiKeyHeader request header when set and present, and
+// fall back to the remote IP otherwise.
+func ClientMetrics(apiKeyHeader string, metrics *prometheusmetrics.ClientMetrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID := clientIDFor(r, apiKeyHeader)
+
+		metrics.Active.Record(clientID)
+		metrics.Requests.Inc(clientID, peekJSONRPCMethod(r))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientIDFor(r *http.Request, apiKeyHeader string) string {
+	if apiKeyHeader != "" {
+		if key := r.Header.Get(apiKeyHeader); key != "" {
+			return key
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// peekJSONRPCMethod reads the request's method without consuming its body
+// for downstream handlers: it buffers the body fully, extracts "method"
+// (or reports "batch" for a JSON-RPC batch array), then restores r.Body so
+// Proxy.ServeHTTP sees the same bytes it otherwise would have.
+func peekJSONRPCMethod(r *http.Request) string {
+	if r.Body == nil {
+		return "unknown"
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "unknown"
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return "unknown"
+	}
+
+	if trimmed[0] == '[' {
+		return "batch"
+	}
+
+	var probe struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(trimmed, &probe); err != nil || probe.Method == "" {
+		return "unknown"
+	}
+
+	return probe.Method
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+)
+
+// maxDecompressedBodySize bounds how much a single request body can expand
+// to once decompressed, so a small gzip-compressed payload can't be used to
+// exhaust memory/upstream bandwidth (a decompression bomb).
+const maxDecompressedBodySize = 10 << 20 // 10 MiB
+
+// Gunzip wraps next so that a gzip-encoded request body is transparently
+// decompressed before reaching it.
+func Gunzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		defer reader.Close() // nolint:errcheck
+
+		// r.ContentLength is the size of the still-compressed body, not the
+		// decompressed stream, so it can't be used to cap reads here; cap
+		// the decompressed stream directly instead.
+		r.Body = http.MaxBytesReader(w, reader, maxDecompressedBodySize)
+		r.ContentLength = -1
+		r.Header.Del("Content-Encoding")
+
+		next.ServeHTTP(w, r)
+	})
+}
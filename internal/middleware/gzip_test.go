@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGunzipRejectsInvalidBody(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not gzip"))
+	w := httptest.NewRecorder()
+
+	Gunzip(next).ServeHTTP(w, r)
+
+	if called {
+		t.Fatalf("next must not be called for a body that isn't valid gzip")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGunzipDecompressesWithinLimit(t *testing.T) {
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	_, err := gw.Write([]byte("hello world"))
+	if err != nil {
+		t.Fatalf("gzip.Write: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip.Close: %v", err)
+	}
+
+	var gotBody []byte
+	next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/", &compressed)
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	Gunzip(next).ServeHTTP(w, r)
+
+	if string(gotBody) != "hello world" {
+		t.Fatalf("got body %q, want decompressed %q", gotBody, "hello world")
+	}
+	if r.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("expected Content-Encoding to be removed once the body is decompressed")
+	}
+}
+
+func TestGunzipCapsDecompressionBombs(t *testing.T) {
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	zeroes := make([]byte, maxDecompressedBodySize+1)
+	if _, err := gw.Write(zeroes); err != nil {
+		t.Fatalf("gzip.Write: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip.Close: %v", err)
+	}
+
+	var readErr error
+	next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/", &compressed)
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	Gunzip(next).ServeHTTP(w, r)
+
+	if readErr == nil {
+		t.Fatalf("expected reading beyond maxDecompressedBodySize to fail")
+	}
+}
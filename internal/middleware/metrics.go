@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/0xProject/rpc-gateway/internal/delegate"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Instrument wraps next so that in-flight, request-size and response-size
+// metrics are updated around every call to it, labeled by provider. It's
+// meant to wrap a single target's ReverseProxy, in the shape of
+// caddyhttp/metrics.go, so response sizes are counted as bytes actually
+// flow through the handler rather than by copying the buffered response
+// afterwards. Request size is taken from r.ContentLength and is not
+// observed when that is unknown or zero.
+func Instrument(provider string, inFlight *prometheus.GaugeVec, requestSize, responseSize *prometheus.HistogramVec, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gauge := inFlight.WithLabelValues(provider)
+		gauge.Inc()
+		defer gauge.Dec()
+
+		if r.ContentLength > 0 {
+			requestSize.WithLabelValues(provider).Observe(float64(r.ContentLength))
+		}
+
+		counter := &sizeCountingWriter{ResponseWriter: w}
+		next.ServeHTTP(withCapabilities(counter, w), r)
+
+		responseSize.WithLabelValues(provider).Observe(float64(counter.written))
+	})
+}
+
+// sizeCountingWriter counts bytes written through it while leaving w
+// itself untouched. It only implements the base http.ResponseWriter
+// interface; withCapabilities adds whichever of http.Flusher,
+// http.CloseNotifier, http.Hijacker and io.ReaderFrom w itself supports,
+// rather than this type claiming all of them unconditionally.
+type sizeCountingWriter struct {
+	http.ResponseWriter
+	written int64
+}
+
+func (w *sizeCountingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+
+	return n, err
+}
+
+func (w *sizeCountingWriter) flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *sizeCountingWriter) closeNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify() // nolint:staticcheck
+}
+
+func (w *sizeCountingWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *sizeCountingWriter) readFrom(src io.Reader) (int64, error) {
+	n, err := w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	w.written += n
+
+	return n, err
+}
+
+// Unwrap exposes the wrapped http.ResponseWriter to http.ResponseController,
+// which unwraps through Unwrap() http.ResponseWriter to reach optional
+// interfaces it doesn't find directly.
+func (w *sizeCountingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withCapabilities wraps counter in whichever combination of
+// http.Flusher, http.CloseNotifier, http.Hijacker and io.ReaderFrom
+// matches what dst itself implements. The matching itself is shared with
+// proxy's ResponseWriter via the delegate package.
+func withCapabilities(counter *sizeCountingWriter, dst http.ResponseWriter) http.ResponseWriter {
+	return delegate.WithCapabilities(counter, dst, delegate.Capabilities{
+		Flush:       counter.flush,
+		CloseNotify: counter.closeNotify,
+		Hijack:      counter.hijack,
+		ReadFrom:    counter.readFrom,
+	})
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// readerFromRecorder is an httptest.ResponseRecorder-like writer that also
+// implements io.ReaderFrom, so Instrument's readFrom delegation path can be
+// exercised the same way a real net/http connection would use it.
+type readerFromRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *readerFromRecorder) Header() http.Header         { return w.header }
+func (w *readerFromRecorder) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *readerFromRecorder) WriteHeader(int)             {}
+
+func (w *readerFromRecorder) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(&w.body, src)
+}
+
+func histogramSum(h prometheus.Histogram) float64 {
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		panic(err)
+	}
+
+	return m.GetHistogram().GetSampleSum()
+}
+
+func TestInstrumentTracksInFlightGaugeAroundNext(t *testing.T) {
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_in_flight"}, []string{"provider"})
+	requestSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_request_size"}, []string{"provider"})
+	responseSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_response_size"}, []string{"provider"})
+
+	var duringCall float64
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		duringCall = testutil.ToFloat64(inFlight.WithLabelValues("target-a"))
+	})
+
+	handler := Instrument("target-a", inFlight, requestSize, responseSize, next)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if duringCall != 1 {
+		t.Fatalf("got in-flight gauge %v during the call, want 1", duringCall)
+	}
+	if got := testutil.ToFloat64(inFlight.WithLabelValues("target-a")); got != 0 {
+		t.Fatalf("got in-flight gauge %v after the call, want 0", got)
+	}
+}
+
+func TestInstrumentObservesRequestAndResponseSize(t *testing.T) {
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_in_flight"}, []string{"provider"})
+	requestSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_request_size"}, []string{"provider"})
+	responseSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_response_size"}, []string{"provider"})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world")) // nolint:errcheck
+	})
+
+	handler := Instrument("target-a", inFlight, requestSize, responseSize, next)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("request body")))
+	r.ContentLength = int64(len("request body"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got := histogramSum(requestSize.WithLabelValues("target-a")); got != float64(len("request body")) {
+		t.Fatalf("got request size histogram sum %v, want %d", got, len("request body"))
+	}
+	if got := histogramSum(responseSize.WithLabelValues("target-a")); got != float64(len("hello world")) {
+		t.Fatalf("got response size histogram sum %v, want %d", got, len("hello world"))
+	}
+}
+
+func TestInstrumentResponseSizeIncludesReadFromPath(t *testing.T) {
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_in_flight"}, []string{"provider"})
+	requestSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_request_size"}, []string{"provider"})
+	responseSize := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_response_size"}, []string{"provider"})
+
+	payload := "streamed response body"
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rf, ok := w.(io.ReaderFrom)
+		if !ok {
+			t.Fatalf("expected Instrument's writer to expose io.ReaderFrom when dst does")
+		}
+		if _, err := rf.ReadFrom(bytes.NewReader([]byte(payload))); err != nil {
+			t.Fatalf("ReadFrom: %v", err)
+		}
+	})
+
+	handler := Instrument("target-a", inFlight, requestSize, responseSize, next)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := &readerFromRecorder{header: http.Header{}}
+	handler.ServeHTTP(w, r)
+
+	if got := histogramSum(responseSize.WithLabelValues("target-a")); got != float64(len(payload)) {
+		t.Fatalf("got response size histogram sum %v, want %d (bytes written via ReadFrom)", got, len(payload))
+	}
+	if w.body.String() != payload {
+		t.Fatalf("got body %q, want %q forwarded through ReadFrom", w.body.String(), payload)
+	}
+}
+
+var (
+	_ http.ResponseWriter = (*readerFromRecorder)(nil)
+	_ io.ReaderFrom       = (*readerFromRecorder)(nil)
+)
@@ -0,0 +1,89 @@
+// Package prometheusmetrics holds metric trackers that need more state
+// than a bare prometheus collector, such as the rolling window of active
+// clients and the cardinality-capped per-client request counter.
+package prometheusmetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// activeClientBuckets is the number of one-minute buckets kept, giving a
+// one-hour rolling window.
+const activeClientBuckets = 60
+
+// ActiveClientsTracker reports the number of distinct clients seen in the
+// past hour via a bounded set of one-minute buckets, so memory stays flat
+// regardless of how many clients churn through over time.
+type ActiveClientsTracker struct {
+	mu      sync.Mutex
+	buckets [activeClientBuckets]map[string]struct{}
+	current int
+	gauge   prometheus.Gauge
+	stopCh  chan struct{}
+}
+
+// NewActiveClientsTracker starts a tracker that rotates its buckets once a
+// minute, updating gauge with the current distinct-client count each time
+// it does.
+func NewActiveClientsTracker(gauge prometheus.Gauge) *ActiveClientsTracker {
+	t := &ActiveClientsTracker{gauge: gauge, stopCh: make(chan struct{})}
+	for i := range t.buckets {
+		t.buckets[i] = make(map[string]struct{})
+	}
+
+	go t.run(time.Minute)
+
+	return t
+}
+
+// Record marks clientID as active in the current minute's bucket. It's an
+// O(1) map write; the gauge itself is only recomputed on rotation so
+// Record never pays for a full-set scan.
+func (t *ActiveClientsTracker) Record(clientID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buckets[t.current][clientID] = struct{}{}
+}
+
+func (t *ActiveClientsTracker) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.rotate()
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+func (t *ActiveClientsTracker) rotate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.current = (t.current + 1) % activeClientBuckets
+	t.buckets[t.current] = make(map[string]struct{})
+	t.gauge.Set(float64(t.unionSizeLocked()))
+}
+
+func (t *ActiveClientsTracker) unionSizeLocked() int {
+	seen := make(map[string]struct{})
+	for _, bucket := range t.buckets {
+		for id := range bucket {
+			seen[id] = struct{}{}
+		}
+	}
+
+	return len(seen)
+}
+
+// Stop ends the rotation goroutine.
+func (t *ActiveClientsTracker) Stop() {
+	close(t.stopCh)
+}
@@ -0,0 +1,63 @@
+package prometheusmetrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestTracker builds an ActiveClientsTracker without starting its
+// rotation goroutine, so the test controls rotation directly instead of
+// waiting on a real one-minute ticker.
+func newTestTracker(gauge prometheus.Gauge) *ActiveClientsTracker {
+	t := &ActiveClientsTracker{gauge: gauge, stopCh: make(chan struct{})}
+	for i := range t.buckets {
+		t.buckets[i] = make(map[string]struct{})
+	}
+
+	return t
+}
+
+func TestActiveClientsTrackerCountsDistinctClients(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_active_clients"})
+	tracker := newTestTracker(gauge)
+
+	tracker.Record("alice")
+	tracker.Record("bob")
+	tracker.Record("alice")
+	tracker.rotate()
+
+	if got := testutil.ToFloat64(gauge); got != 2 {
+		t.Fatalf("got %v distinct clients, want 2", got)
+	}
+}
+
+func TestActiveClientsTrackerRetainsClientsAcrossRotation(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_active_clients_retain"})
+	tracker := newTestTracker(gauge)
+
+	tracker.Record("alice")
+	tracker.rotate()
+	tracker.Record("bob")
+	tracker.rotate()
+
+	if got := testutil.ToFloat64(gauge); got != 2 {
+		t.Fatalf("got %v distinct clients, want 2 (alice from the older bucket plus bob)", got)
+	}
+}
+
+func TestActiveClientsTrackerDropsClientsThatAgeOutOfTheWindow(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_active_clients_expire"})
+	tracker := newTestTracker(gauge)
+
+	tracker.Record("alice")
+
+	for i := 0; i < activeClientBuckets; i++ {
+		tracker.rotate()
+	}
+
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Fatalf("got %v distinct clients, want 0 once alice's bucket has rotated out of the window", got)
+	}
+}
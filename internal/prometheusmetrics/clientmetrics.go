@@ -0,0 +1,35 @@
+package prometheusmetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ClientMetrics bundles the active-clients gauge and the per-client
+// request counter so callers (the middleware package, in practice) only
+// need to thread one value through.
+type ClientMetrics struct {
+	Active   *ActiveClientsTracker
+	Requests *ClientRequestsRecorder
+}
+
+// NewClientMetrics registers zeroex_rpc_gateway_active_clients and
+// zeroex_rpc_gateway_client_requests_total and wires them into trackers.
+// maxCardinality bounds the number of distinct client_id label values the
+// request counter will create; see ClientRequestsRecorder.
+func NewClientMetrics(maxCardinality int) *ClientMetrics {
+	activeGauge := promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "zeroex_rpc_gateway_active_clients",
+		Help: "Number of distinct clients that made at least one request in the past hour",
+	})
+
+	requestsCounter := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "zeroex_rpc_gateway_client_requests_total",
+		Help: "Total number of requests per client and JSON-RPC method",
+	}, []string{"client_id", "method"})
+
+	return &ClientMetrics{
+		Active:   NewActiveClientsTracker(activeGauge),
+		Requests: NewClientRequestsRecorder(requestsCounter, maxCardinality),
+	}
+}
@@ -0,0 +1,67 @@
+package prometheusmetrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClientRequestsRecorder emits zeroex_rpc_gateway_client_requests_total,
+// capping the number of distinct client_id label values it will create: once
+// maxCardinality distinct clients have been seen, any further client is
+// folded into one of maxCardinality "overflow-*" buckets instead of
+// getting its own series, so a long tail of one-off clients can't blow up
+// label cardinality.
+type ClientRequestsRecorder struct {
+	mu             sync.Mutex
+	seen           map[string]struct{}
+	maxCardinality int
+	counter        *prometheus.CounterVec
+}
+
+// NewClientRequestsRecorder builds a recorder around counter. A
+// maxCardinality of 0 disables the cap.
+func NewClientRequestsRecorder(counter *prometheus.CounterVec, maxCardinality int) *ClientRequestsRecorder {
+	return &ClientRequestsRecorder{
+		seen:           make(map[string]struct{}),
+		maxCardinality: maxCardinality,
+		counter:        counter,
+	}
+}
+
+// Inc records one request from clientID against method.
+func (r *ClientRequestsRecorder) Inc(clientID, method string) {
+	r.counter.WithLabelValues(r.label(clientID), method).Inc()
+}
+
+func (r *ClientRequestsRecorder) label(clientID string) string {
+	if r.maxCardinality <= 0 {
+		return clientID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.seen[clientID]; ok {
+		return clientID
+	}
+
+	if len(r.seen) < r.maxCardinality {
+		r.seen[clientID] = struct{}{}
+		return clientID
+	}
+
+	return overflowBucket(clientID, r.maxCardinality)
+}
+
+// overflowBucket deterministically hashes clientID down to one of
+// buckets fixed label values, so clients past the cardinality cap still
+// share a small, bounded set of series instead of each minting their own.
+func overflowBucket(clientID string, buckets int) string {
+	h := fnv.New32a()
+	h.Write([]byte(clientID)) // nolint:errcheck
+
+	return fmt.Sprintf("overflow-%x", h.Sum32()%uint32(buckets))
+}
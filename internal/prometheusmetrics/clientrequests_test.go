@@ -0,0 +1,71 @@
+package prometheusmetrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClientRequestsRecorderUsesClientIDUnderTheCardinalityCap(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_client_requests_total"}, []string{"client_id", "method"})
+	recorder := NewClientRequestsRecorder(counter, 2)
+
+	recorder.Inc("alice", "eth_call")
+
+	if got := testutil.ToFloat64(counter.WithLabelValues("alice", "eth_call")); got != 1 {
+		t.Fatalf("got %v, want 1 request labeled with the real client_id", got)
+	}
+}
+
+func TestClientRequestsRecorderFoldsClientsPastTheCapIntoAnOverflowBucket(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_client_requests_total_overflow"}, []string{"client_id", "method"})
+	recorder := NewClientRequestsRecorder(counter, 1)
+
+	recorder.Inc("alice", "eth_call")
+	recorder.Inc("bob", "eth_call")
+
+	wantBucket := overflowBucket("bob", 1)
+	if got := testutil.ToFloat64(counter.WithLabelValues(wantBucket, "eth_call")); got != 1 {
+		t.Fatalf("got %v, want bob's request counted under overflow bucket %q", got, wantBucket)
+	}
+	if got := testutil.ToFloat64(counter.WithLabelValues("bob", "eth_call")); got != 0 {
+		t.Fatalf("got %v, want no series created under bob's own client_id once past the cap", got)
+	}
+}
+
+func TestClientRequestsRecorderOverflowIsDeterministicAndBounded(t *testing.T) {
+	const buckets = 4
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		clientID := fmt.Sprintf("client-%d", i)
+
+		first := overflowBucket(clientID, buckets)
+		second := overflowBucket(clientID, buckets)
+		if first != second {
+			t.Fatalf("overflowBucket(%q) not deterministic: got %q then %q", clientID, first, second)
+		}
+		seen[first] = struct{}{}
+	}
+
+	if len(seen) > buckets {
+		t.Fatalf("got %d distinct overflow buckets, want at most %d", len(seen), buckets)
+	}
+}
+
+func TestClientRequestsRecorderDisablesCapAtZero(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_client_requests_total_nocap"}, []string{"client_id", "method"})
+	recorder := NewClientRequestsRecorder(counter, 0)
+
+	recorder.Inc("alice", "eth_call")
+	recorder.Inc("bob", "eth_call")
+
+	if got := testutil.ToFloat64(counter.WithLabelValues("alice", "eth_call")); got != 1 {
+		t.Fatalf("got %v, want alice's own client_id used when the cap is disabled", got)
+	}
+	if got := testutil.ToFloat64(counter.WithLabelValues("bob", "eth_call")); got != 1 {
+		t.Fatalf("got %v, want bob's own client_id used when the cap is disabled", got)
+	}
+}
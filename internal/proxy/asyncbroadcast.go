@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// asyncJob is one fire-and-forget submission queued for background
+// broadcast to every healthy target.
+type asyncJob struct {
+	req *http.Request
+	rpc *JSONRPCRequest
+}
+
+// metricBroadcast is shared across every AsyncBroadcaster instance. It must
+// be registered once at package scope rather than inside NewAsyncBroadcaster,
+// which can run more than once per process (e.g. once per test).
+var metricBroadcast = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "zeroex_rpc_gateway_async_broadcast_total",
+	Help: "Total number of async broadcast attempts per provider and result",
+}, []string{
+	"provider",
+	"result",
+})
+
+// AsyncBroadcaster fans queued JSON-RPC requests out to every healthy
+// target in the background, independently of the client connection that
+// submitted them. It bounds its queue so a burst of submissions applies
+// backpressure instead of growing memory without limit.
+type AsyncBroadcaster struct {
+	queue              chan asyncJob
+	wg                 sync.WaitGroup
+	submitMu           sync.Mutex
+	closing            bool
+	dispatch           forwardFunc
+	targets            []*HTTPTarget
+	healthcheckManager *HealthcheckManager
+	failed             func(int) bool
+}
+
+// NewAsyncBroadcaster starts workers consuming the queue in the
+// background. Call Shutdown to drain it during server shutdown.
+func NewAsyncBroadcaster(config AsyncConfig, targets []*HTTPTarget, healthcheckManager *HealthcheckManager, dispatch forwardFunc, failed func(int) bool) *AsyncBroadcaster {
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = 256
+	}
+
+	workers := config.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	b := &AsyncBroadcaster{
+		queue:              make(chan asyncJob, queueSize),
+		dispatch:           dispatch,
+		targets:            targets,
+		healthcheckManager: healthcheckManager,
+		failed:             failed,
+	}
+
+	for i := 0; i < workers; i++ {
+		b.wg.Add(1)
+		go b.worker()
+	}
+
+	return b
+}
+
+// Submit enqueues a job for background broadcast. It returns false without
+// blocking if the queue is full or the broadcaster is shutting down, so
+// the caller can answer the client with 503 instead of buffering
+// unboundedly. submitMu is held across the closing check and the send so
+// Shutdown can't close the queue in between and turn this into a send on
+// a closed channel.
+func (b *AsyncBroadcaster) Submit(r *http.Request, rpc *JSONRPCRequest) bool {
+	b.submitMu.Lock()
+	defer b.submitMu.Unlock()
+
+	if b.closing {
+		return false
+	}
+
+	// net/http cancels r.Context() the instant the handler that received r
+	// returns, which happens right after Submit since serveAsync answers
+	// 202 Accepted immediately. Detach the job from that context now, while
+	// r is still live, so the worker that broadcasts it later isn't handed
+	// an already-canceled request.
+	detached := r.Clone(context.Background())
+
+	select {
+	case b.queue <- asyncJob{req: detached, rpc: rpc}:
+		return true
+	default:
+		return false
+	}
+}
+
+// worker drains the queue until Shutdown closes it, broadcasting whatever
+// was already queued before returning.
+func (b *AsyncBroadcaster) worker() {
+	defer b.wg.Done()
+
+	for job := range b.queue {
+		b.broadcast(job)
+	}
+}
+
+func (b *AsyncBroadcaster) broadcast(job asyncJob) {
+	var wg sync.WaitGroup
+
+	for _, target := range b.targets {
+		if !b.healthcheckManager.IsHealthy(target.Config.Name) {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(target *HTTPTarget) {
+			defer wg.Done()
+
+			pw := b.dispatch(target, noopResponseWriter{}, job.req, job.rpc.raw, job.rpc.Method)
+
+			result := "success"
+			if b.failed(pw.statusCode) {
+				result = "failure"
+			}
+
+			metricBroadcast.WithLabelValues(target.Config.Name, result).Inc()
+		}(target)
+	}
+
+	wg.Wait()
+}
+
+// Shutdown stops accepting new submissions and blocks until every
+// already-queued job has either been broadcast or ctx is done, whichever
+// comes first. It is meant to be called from the same place
+// http.Server.Shutdown is, so in-flight broadcasts aren't abandoned mid
+// rollout.
+func (b *AsyncBroadcaster) Shutdown(ctx context.Context) error {
+	b.submitMu.Lock()
+	b.closing = true
+	close(b.queue)
+	b.submitMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// noopResponseWriter satisfies http.ResponseWriter for the background
+// broadcast path, which never relays anything back to a client: the
+// client already got its 202 Accepted before the broadcast started.
+type noopResponseWriter struct{}
+
+func (noopResponseWriter) Header() http.Header         { return http.Header{} }
+func (noopResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (noopResponseWriter) WriteHeader(int)             {}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestAsyncBroadcaster(dispatch forwardFunc) (*AsyncBroadcaster, []*HTTPTarget) {
+	targets := []*HTTPTarget{{Config: TargetConfig{Name: "target-a"}}}
+	healthcheckManager := NewHealthcheckManager(Config{Targets: []TargetConfig{targets[0].Config}})
+	failed := func(statusCode int) bool { return statusCode >= 500 }
+
+	return NewAsyncBroadcaster(AsyncConfig{}, targets, healthcheckManager, dispatch, failed), targets
+}
+
+func TestAsyncBroadcasterSubmitAfterShutdownFails(t *testing.T) {
+	b, _ := newTestAsyncBroadcaster(func(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+		return NewResponseWriter(w)
+	})
+
+	if err := b.Shutdown(httptest.NewRequest(http.MethodPost, "/", nil).Context()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	if b.Submit(r, &JSONRPCRequest{Method: "eth_sendRawTransaction"}) {
+		t.Fatalf("Submit must fail once the broadcaster is shutting down")
+	}
+}
+
+func TestAsyncBroadcasterSubmitConcurrentWithShutdownDoesNotPanic(t *testing.T) {
+	b, _ := newTestAsyncBroadcaster(func(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+		return NewResponseWriter(w)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			b.Submit(r, &JSONRPCRequest{Method: "eth_sendRawTransaction"})
+		}()
+	}
+
+	if err := b.Shutdown(httptest.NewRequest(http.MethodPost, "/", nil).Context()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	wg.Wait()
+}
+
+func TestAsyncBroadcasterSubmitDetachesRequestContext(t *testing.T) {
+	dispatched := make(chan *http.Request, 1)
+	b, _ := newTestAsyncBroadcaster(func(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+		dispatched <- r
+		return NewResponseWriter(w)
+	})
+	defer b.Shutdown(httptest.NewRequest(http.MethodPost, "/", nil).Context()) // nolint:errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+	if !b.Submit(req, &JSONRPCRequest{Method: "eth_sendRawTransaction", raw: []byte("{}")}) {
+		t.Fatalf("expected Submit to succeed")
+	}
+
+	// Simulate net/http canceling the original request's context the
+	// instant the handler that received it returns.
+	cancel()
+
+	select {
+	case dispatchedReq := <-dispatched:
+		if err := dispatchedReq.Context().Err(); err != nil {
+			t.Fatalf("expected the job's request context to survive the original request's cancellation, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("broadcast worker never dispatched the job")
+	}
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreakerState is the state of a CircuitBreaker.
+type CircuitBreakerState int
+
+const (
+	CircuitClosed CircuitBreakerState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// DefaultCircuitBreakerConfig is used for any target that doesn't set its
+// own CircuitBreakerConfig.
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureThreshold: 5,
+	Window:           time.Minute,
+	OpenDuration:     30 * time.Second,
+}
+
+// CircuitBreaker trips a target out of rotation once it has failed
+// FailureThreshold times within Window, and admits a single probe request
+// after OpenDuration to decide whether it can close again. Healthchecks and
+// live traffic share the same breaker, so either can trip or reset it.
+type CircuitBreaker struct {
+	mu sync.Mutex
+
+	config CircuitBreakerConfig
+	state  CircuitBreakerState
+
+	failures  []time.Time
+	openUntil time.Time
+}
+
+// NewCircuitBreaker builds a closed CircuitBreaker from config, falling
+// back to DefaultCircuitBreakerConfig for any zero field.
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultCircuitBreakerConfig.FailureThreshold
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultCircuitBreakerConfig.Window
+	}
+	if config.OpenDuration <= 0 {
+		config.OpenDuration = DefaultCircuitBreakerConfig.OpenDuration
+	}
+
+	return &CircuitBreaker{config: config, state: CircuitClosed}
+}
+
+// Allow reports whether a request should be let through. An open breaker
+// transitions to half-open (and allows exactly the request that discovers
+// this) once OpenDuration has elapsed.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+
+		b.state = CircuitHalfOpen
+
+		return true
+	case CircuitHalfOpen:
+		// Only let the probe already in flight through; everything else
+		// waits for it to resolve the state.
+		return false
+	default:
+		return true
+	}
+}
+
+// RecordResult feeds the outcome of a request (or healthcheck) back into
+// the breaker.
+func (b *CircuitBreaker) RecordResult(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = CircuitClosed
+		b.failures = nil
+
+		return
+	}
+
+	if b.state == CircuitHalfOpen {
+		b.trip()
+
+		return
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-b.config.Window)
+
+	kept := b.failures[:0]
+	for _, t := range b.failures {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+
+	b.failures = append(kept, now)
+
+	if len(b.failures) >= b.config.FailureThreshold {
+		b.trip()
+	}
+}
+
+// trip must be called with b.mu held.
+func (b *CircuitBreaker) trip() {
+	b.state = CircuitOpen
+	b.openUntil = time.Now().Add(b.config.OpenDuration)
+	b.failures = nil
+}
+
+// State returns the breaker's current state.
+func (b *CircuitBreaker) State() CircuitBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
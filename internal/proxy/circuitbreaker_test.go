@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func testCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: 2,
+		Window:           time.Minute,
+		OpenDuration:     10 * time.Millisecond,
+	}
+}
+
+func TestCircuitBreakerStartsClosed(t *testing.T) {
+	b := NewCircuitBreaker(testCircuitBreakerConfig())
+
+	if b.State() != CircuitClosed {
+		t.Fatalf("expected CircuitClosed, got %s", b.State())
+	}
+	if !b.Allow() {
+		t.Fatalf("a closed breaker must allow requests")
+	}
+}
+
+func TestCircuitBreakerTripsAfterFailureThreshold(t *testing.T) {
+	b := NewCircuitBreaker(testCircuitBreakerConfig())
+
+	b.RecordResult(false)
+	if b.State() != CircuitClosed {
+		t.Fatalf("breaker must stay closed below the failure threshold, got %s", b.State())
+	}
+
+	b.RecordResult(false)
+	if b.State() != CircuitOpen {
+		t.Fatalf("expected CircuitOpen after reaching the failure threshold, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Fatalf("an open breaker must not allow requests before OpenDuration elapses")
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	b := NewCircuitBreaker(testCircuitBreakerConfig())
+
+	b.RecordResult(false)
+	b.RecordResult(false)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatalf("expected the first request after OpenDuration to be allowed as a probe")
+	}
+	if b.State() != CircuitHalfOpen {
+		t.Fatalf("expected CircuitHalfOpen after the probe is admitted, got %s", b.State())
+	}
+	if b.Allow() {
+		t.Fatalf("a half-open breaker must only admit the probe already in flight")
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	b := NewCircuitBreaker(testCircuitBreakerConfig())
+
+	b.RecordResult(false)
+	b.RecordResult(false)
+	time.Sleep(20 * time.Millisecond)
+	b.Allow() // admit the probe, moving to half-open
+
+	b.RecordResult(false)
+
+	if b.State() != CircuitOpen {
+		t.Fatalf("a failed probe must reopen the breaker, got %s", b.State())
+	}
+}
+
+func TestCircuitBreakerHalfOpenSuccessCloses(t *testing.T) {
+	b := NewCircuitBreaker(testCircuitBreakerConfig())
+
+	b.RecordResult(false)
+	b.RecordResult(false)
+	time.Sleep(20 * time.Millisecond)
+	b.Allow() // admit the probe, moving to half-open
+
+	b.RecordResult(true)
+
+	if b.State() != CircuitClosed {
+		t.Fatalf("a successful probe must close the breaker, got %s", b.State())
+	}
+}
+
+func TestCircuitBreakerFailuresOutsideWindowDontAccumulate(t *testing.T) {
+	config := testCircuitBreakerConfig()
+	config.Window = 10 * time.Millisecond
+	b := NewCircuitBreaker(config)
+
+	b.RecordResult(false)
+	time.Sleep(20 * time.Millisecond)
+	b.RecordResult(false)
+
+	if b.State() != CircuitClosed {
+		t.Fatalf("failures outside the window must not accumulate toward the threshold, got %s", b.State())
+	}
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config is the root configuration for the proxy.
+type Config struct {
+	Proxy   ProxyConfig    `yaml:"proxy"`
+	Targets []TargetConfig `yaml:"targets"`
+}
+
+// ProxyConfig holds settings that apply to the proxy as a whole.
+type ProxyConfig struct {
+	UpstreamTimeout time.Duration       `yaml:"upstreamTimeout"`
+	Async           AsyncConfig         `yaml:"async"`
+	ClientMetrics   ClientMetricsConfig `yaml:"clientMetrics"`
+}
+
+// ClientMetricsConfig configures how clients are identified for the
+// active-clients gauge and per-client request counter.
+type ClientMetricsConfig struct {
+	APIKeyHeader   string `yaml:"apiKeyHeader"`
+	MaxCardinality int    `yaml:"maxCardinality"`
+}
+
+// AsyncConfig enables fire-and-forget submission for a set of JSON-RPC
+// methods: instead of waiting on an upstream, the gateway answers
+// immediately and broadcasts to every healthy target in the background.
+// When Header is set, only requests that carry that header are handled
+// this way.
+type AsyncConfig struct {
+	Methods      []string      `yaml:"methods"`
+	Header       string        `yaml:"header"`
+	QueueSize    int           `yaml:"queueSize"`
+	Workers      int           `yaml:"workers"`
+	DrainTimeout time.Duration `yaml:"drainTimeout"`
+}
+
+// TargetConfig describes a single upstream RPC node and how the proxy
+// should talk to it.
+type TargetConfig struct {
+	Name           string                  `yaml:"name"`
+	URL            string                  `yaml:"connection"`
+	Connection     ConnectionConfig        `yaml:"connectionConfig"`
+	Methods        map[string]MethodConfig `yaml:"methods"`
+	RetryPolicy    RetryPolicyConfig       `yaml:"retryPolicy"`
+	CircuitBreaker CircuitBreakerConfig    `yaml:"circuitBreaker"`
+}
+
+// RetryPolicyConfig configures exponential backoff with full jitter between
+// retry attempts against a single target. Zero values fall back to the
+// package defaults in DefaultRetryPolicy.
+type RetryPolicyConfig struct {
+	Base        time.Duration `yaml:"base"`
+	Cap         time.Duration `yaml:"cap"`
+	MaxAttempts int           `yaml:"maxAttempts"`
+}
+
+// CircuitBreakerConfig configures when a target is tripped out of rotation
+// after consecutive failures, and how long it stays there before a probe
+// is allowed through again.
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failureThreshold"`
+	Window           time.Duration `yaml:"window"`
+	OpenDuration     time.Duration `yaml:"openDuration"`
+}
+
+// MethodConfig overrides retry/timeout/backoff behaviour for a single
+// JSON-RPC method against a target, analogous to per-backend retry rules
+// in a backends.yaml style config. A zero value means "use the target's
+// defaults".
+type MethodConfig struct {
+	Retries int           `yaml:"retries"`
+	Delay   time.Duration `yaml:"delay"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// ConnectionConfig groups the transport-level knobs for a target.
+type ConnectionConfig struct {
+	HTTP HTTPConfig `yaml:"http"`
+}
+
+// HTTPConfig controls how the reverse proxy dials and speaks to a target.
+type HTTPConfig struct {
+	Compression bool       `yaml:"compression"`
+	Pool        PoolConfig `yaml:"pool"`
+}
+
+// PoolConfig tunes the http.Transport connection pool used to reach a
+// single target.
+type PoolConfig struct {
+	MaxIdleConns          int           `yaml:"maxIdleConns"`
+	MaxConnsPerHost       int           `yaml:"maxConnsPerHost"`
+	IdleConnTimeout       time.Duration `yaml:"idleConnTimeout"`
+	DialTimeout           time.Duration `yaml:"dialTimeout"`
+	TLSHandshakeTimeout   time.Duration `yaml:"tlsHandshakeTimeout"`
+	ExpectContinueTimeout time.Duration `yaml:"expectContinueTimeout"`
+	DisableHTTP2          bool          `yaml:"disableHTTP2"`
+	WarmupConnections     int           `yaml:"warmupConnections"`
+
+	// ClientCertFile and ClientKeyFile, when both set, enable mTLS to the
+	// target using the given client certificate.
+	ClientCertFile string `yaml:"clientCertFile"`
+	ClientKeyFile  string `yaml:"clientKeyFile"`
+}
+
+// Validate reports whether the pool config is internally consistent,
+// mirroring the sanity checks the config loader runs on the rest of
+// TargetConfig.
+func (c PoolConfig) Validate() error {
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		return fmt.Errorf("pool: clientCertFile and clientKeyFile must both be set or both be empty")
+	}
+
+	return nil
+}
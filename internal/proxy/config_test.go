@@ -0,0 +1,28 @@
+package proxy
+
+import "testing"
+
+func TestPoolConfigValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  PoolConfig
+		wantErr bool
+	}{
+		{name: "neither cert field set", config: PoolConfig{}},
+		{name: "both cert fields set", config: PoolConfig{ClientCertFile: "cert.pem", ClientKeyFile: "key.pem"}},
+		{name: "cert without key", config: PoolConfig{ClientCertFile: "cert.pem"}, wantErr: true},
+		{name: "key without cert", config: PoolConfig{ClientKeyFile: "key.pem"}, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.config.Validate()
+			if c.wantErr && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !c.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
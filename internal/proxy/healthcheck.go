@@ -0,0 +1,69 @@
+package proxy
+
+// HealthcheckManager tracks the liveness of each configured target and
+// answers whether a given target is currently eligible for traffic. It
+// shares its per-target CircuitBreaker with live traffic, so a string of
+// failed requests trips a target out of rotation the same way a failed
+// healthcheck probe would.
+type HealthcheckManager struct {
+	targets  map[string]bool
+	breakers map[string]*CircuitBreaker
+}
+
+// NewHealthcheckManager returns a manager with every target assumed
+// healthy until proven otherwise.
+func NewHealthcheckManager(config Config) *HealthcheckManager {
+	targets := make(map[string]bool, len(config.Targets))
+	breakers := make(map[string]*CircuitBreaker, len(config.Targets))
+
+	for _, target := range config.Targets {
+		targets[target.Name] = true
+		breakers[target.Name] = NewCircuitBreaker(target.CircuitBreaker)
+	}
+
+	return &HealthcheckManager{targets: targets, breakers: breakers}
+}
+
+// IsHealthy reports whether the named target is currently considered
+// healthy and its circuit breaker allows a request: the breaker is closed,
+// or it is open past OpenDuration and this call is admitted as the
+// half-open probe. While a probe is outstanding it reports false.
+func (h *HealthcheckManager) IsHealthy(name string) bool {
+	healthy, ok := h.targets[name]
+	if ok && !healthy {
+		return false
+	}
+
+	if breaker, ok := h.breakers[name]; ok {
+		return breaker.Allow()
+	}
+
+	return true
+}
+
+// SetHealthy records the outcome of a healthcheck probe for a target and
+// feeds it into the target's circuit breaker.
+func (h *HealthcheckManager) SetHealthy(name string, healthy bool) {
+	h.targets[name] = healthy
+
+	if breaker, ok := h.breakers[name]; ok {
+		breaker.RecordResult(healthy)
+	}
+}
+
+// RecordRequestResult feeds the outcome of a live request into a target's
+// circuit breaker without touching its healthcheck-derived liveness flag,
+// so a handful of live-traffic failures trips the breaker the same way a
+// failed healthcheck probe would, while SetHealthy remains the source of
+// truth for whether the target is known down.
+func (h *HealthcheckManager) RecordRequestResult(name string, success bool) {
+	if breaker, ok := h.breakers[name]; ok {
+		breaker.RecordResult(success)
+	}
+}
+
+// Breaker returns the circuit breaker for a target, or nil if none is
+// registered (e.g. the target isn't part of the static config).
+func (h *HealthcheckManager) Breaker(name string) *CircuitBreaker {
+	return h.breakers[name]
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// stateChangingMethods are JSON-RPC methods that mutate chain state. Unlike
+// read-only calls, these are broadcast to every healthy target instead of
+// being tried one target at a time.
+var stateChangingMethods = map[string]bool{
+	"eth_sendRawTransaction": true,
+	"eth_sendTransaction":    true,
+}
+
+// JSONRPCRequest is a single JSON-RPC 2.0 request object, as it appears
+// either standalone or as an element of a batch array.
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+
+	raw []byte
+}
+
+// JSONRPCResponse is a single JSON-RPC 2.0 response object.
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// IsNotification reports whether the request has no id and therefore
+// expects no response.
+func (r *JSONRPCRequest) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// IsStateChanging reports whether the method mutates chain state and
+// should be broadcast to every healthy target rather than routed to one.
+func (r *JSONRPCRequest) IsStateChanging() bool {
+	return stateChangingMethods[r.Method]
+}
+
+// ParsedBody is the result of parsing an incoming HTTP body as JSON-RPC.
+// It is computed once per request and reused across retries so each
+// target attempt doesn't have to re-parse and re-validate the payload.
+type ParsedBody struct {
+	Batch    bool
+	Requests []*JSONRPCRequest
+	// Valid is false when the body wasn't valid JSON-RPC, in which case
+	// the original bytes should be forwarded to a single upstream
+	// unchanged instead of being routed per-method.
+	Valid bool
+}
+
+// ParseJSONRPCBody parses a raw HTTP body into its JSON-RPC request(s).
+// Invalid JSON, or JSON that isn't a JSON-RPC object/array, is reported via
+// Valid=false rather than an error so callers can fall back to forwarding
+// the body unchanged.
+func ParseJSONRPCBody(body []byte) *ParsedBody {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return &ParsedBody{Valid: false}
+	}
+
+	switch trimmed[0] {
+	case '[':
+		var raw []json.RawMessage
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return &ParsedBody{Valid: false}
+		}
+
+		requests := make([]*JSONRPCRequest, 0, len(raw))
+		for _, r := range raw {
+			req, err := decodeJSONRPCRequest(r)
+			if err != nil {
+				return &ParsedBody{Valid: false}
+			}
+			requests = append(requests, req)
+		}
+
+		return &ParsedBody{Batch: true, Requests: requests, Valid: true}
+	case '{':
+		req, err := decodeJSONRPCRequest(trimmed)
+		if err != nil {
+			return &ParsedBody{Valid: false}
+		}
+
+		return &ParsedBody{Requests: []*JSONRPCRequest{req}, Valid: true}
+	default:
+		return &ParsedBody{Valid: false}
+	}
+}
+
+func decodeJSONRPCRequest(raw json.RawMessage) (*JSONRPCRequest, error) {
+	req := &JSONRPCRequest{}
+	if err := json.Unmarshal(raw, req); err != nil {
+		return nil, err
+	}
+
+	req.raw = append([]byte(nil), raw...)
+
+	return req, nil
+}
+
+// jsonRPCServiceUnavailable is the JSON-RPC 2.0 error object reported for a
+// batch entry whose request failed against every healthy target.
+var jsonRPCServiceUnavailable = json.RawMessage(`{"code":-32000,"message":"Service Unavailable"}`)
+
+// MarshalBatchResponse reassembles per-request responses into a batch
+// response, preserving the ordering of the original request array.
+// requests and responses must be parallel slices, as JSONRPCRouter.Route
+// builds them. A notification (no id) contributes no entry to the result.
+// A nil response for a non-notification request means every healthy
+// target failed it; that still needs an entry, so the caller can tell
+// which request it was, and gets a JSON-RPC error object rather than being
+// silently dropped.
+func MarshalBatchResponse(requests []*JSONRPCRequest, responses []*JSONRPCResponse) ([]byte, error) {
+	out := make([]*JSONRPCResponse, 0, len(responses))
+	for i, r := range responses {
+		if requests[i].IsNotification() {
+			continue
+		}
+
+		if r == nil {
+			r = &JSONRPCResponse{JSONRPC: "2.0", ID: requests[i].ID, Error: jsonRPCServiceUnavailable}
+		}
+
+		out = append(out, r)
+	}
+
+	return json.Marshal(out)
+}
@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseJSONRPCBodySingle(t *testing.T) {
+	parsed := ParseJSONRPCBody([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`))
+
+	if !parsed.Valid {
+		t.Fatalf("expected valid parse")
+	}
+	if parsed.Batch {
+		t.Fatalf("single request must not be reported as a batch")
+	}
+	if len(parsed.Requests) != 1 || parsed.Requests[0].Method != "eth_blockNumber" {
+		t.Fatalf("unexpected requests: %+v", parsed.Requests)
+	}
+}
+
+func TestParseJSONRPCBodyBatch(t *testing.T) {
+	parsed := ParseJSONRPCBody([]byte(`[{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"},{"jsonrpc":"2.0","id":2,"method":"eth_chainId"}]`))
+
+	if !parsed.Valid || !parsed.Batch {
+		t.Fatalf("expected a valid batch, got %+v", parsed)
+	}
+	if len(parsed.Requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(parsed.Requests))
+	}
+}
+
+func TestParseJSONRPCBodyNotification(t *testing.T) {
+	parsed := ParseJSONRPCBody([]byte(`{"jsonrpc":"2.0","method":"eth_subscribe"}`))
+
+	if !parsed.Valid {
+		t.Fatalf("expected valid parse")
+	}
+	if !parsed.Requests[0].IsNotification() {
+		t.Fatalf("request without id must be reported as a notification")
+	}
+}
+
+func TestParseJSONRPCBodyInvalid(t *testing.T) {
+	cases := [][]byte{
+		nil,
+		[]byte(`   `),
+		[]byte(`not json`),
+		[]byte(`{"jsonrpc":`),
+		[]byte(`[{"jsonrpc":"2.0","id":1},{not valid}]`),
+	}
+
+	for _, body := range cases {
+		if ParseJSONRPCBody(body).Valid {
+			t.Fatalf("expected invalid parse for %q", body)
+		}
+	}
+}
+
+func TestJSONRPCRequestIsStateChanging(t *testing.T) {
+	if (&JSONRPCRequest{Method: "eth_call"}).IsStateChanging() {
+		t.Fatalf("eth_call must not be treated as state-changing")
+	}
+	if !(&JSONRPCRequest{Method: "eth_sendRawTransaction"}).IsStateChanging() {
+		t.Fatalf("eth_sendRawTransaction must be treated as state-changing")
+	}
+}
+
+func TestMarshalBatchResponsePreservesOrderAndDropsNotifications(t *testing.T) {
+	requests := []*JSONRPCRequest{
+		{ID: json.RawMessage(`2`)},
+		{}, // a notification: no id, expects no response
+		{ID: json.RawMessage(`1`)},
+	}
+	responses := []*JSONRPCResponse{
+		{JSONRPC: "2.0", ID: json.RawMessage(`2`)},
+		nil,
+		{JSONRPC: "2.0", ID: json.RawMessage(`1`)},
+	}
+
+	body, err := MarshalBatchResponse(requests, responses)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out []JSONRPCResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("unexpected error unmarshaling result: %v", err)
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(out))
+	}
+	if string(out[0].ID) != "2" || string(out[1].ID) != "1" {
+		t.Fatalf("responses must preserve the original request order, got %+v", out)
+	}
+}
+
+func TestMarshalBatchResponseReportsErrorForFailedRequest(t *testing.T) {
+	requests := []*JSONRPCRequest{
+		{ID: json.RawMessage(`1`)},
+		{ID: json.RawMessage(`2`)},
+	}
+	responses := []*JSONRPCResponse{
+		{JSONRPC: "2.0", ID: json.RawMessage(`1`)},
+		nil, // every healthy target failed this one
+	}
+
+	body, err := MarshalBatchResponse(requests, responses)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out []JSONRPCResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("unexpected error unmarshaling result: %v", err)
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("expected an entry for every non-notification request, got %d: %+v", len(out), out)
+	}
+	if string(out[1].ID) != "2" {
+		t.Fatalf("got id %s, want the failed request's id 2", out[1].ID)
+	}
+	if len(out[1].Error) == 0 {
+		t.Fatalf("expected a JSON-RPC error object for the failed request, got %+v", out[1])
+	}
+}
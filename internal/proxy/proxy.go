@@ -2,6 +2,8 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httputil"
@@ -10,24 +12,36 @@ import (
 	"time"
 
 	"github.com/0xProject/rpc-gateway/internal/middleware"
+	"github.com/0xProject/rpc-gateway/internal/prometheusmetrics"
 	"github.com/go-http-utils/headers"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 type HTTPTarget struct {
-	Config TargetConfig
-	Proxy  *httputil.ReverseProxy
+	Config      TargetConfig
+	Proxy       *httputil.ReverseProxy
+	RetryPolicy RetryPolicy
 }
 
 type Proxy struct {
 	config             Config
 	targets            []*HTTPTarget
 	healthcheckManager *HealthcheckManager
+	jsonrpcRouter      *JSONRPCRouter
+	asyncBroadcaster   *AsyncBroadcaster
+	asyncMethods       map[string]bool
+	clientMetrics      *prometheusmetrics.ClientMetrics
+	handler            http.Handler
 
-	metricResponseTime   *prometheus.HistogramVec
-	metricRequestErrors  *prometheus.CounterVec
-	metricResponseStatus *prometheus.CounterVec
+	metricResponseTime     *prometheus.HistogramVec
+	metricRequestErrors    *prometheus.CounterVec
+	metricResponseStatus   *prometheus.CounterVec
+	metricCircuitState     *prometheus.GaugeVec
+	metricBackendRetries   *prometheus.CounterVec
+	metricRequestsInFlight *prometheus.GaugeVec
+	metricRequestSize      *prometheus.HistogramVec
+	metricResponseSize     *prometheus.HistogramVec
 }
 
 func NewProxy(proxyConfig Config, healthCheckManager *HealthcheckManager) *Proxy {
@@ -72,6 +86,38 @@ func NewProxy(proxyConfig Config, healthCheckManager *HealthcheckManager) *Proxy
 			"provider",
 			"status_code",
 		}),
+		metricCircuitState: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "zeroex_rpc_gateway_circuit_state",
+			Help: "Circuit breaker state per provider (0=closed, 1=half_open, 2=open)",
+		}, []string{
+			"provider",
+		}),
+		metricBackendRetries: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "zeroex_rpc_gateway_backend_retries_total",
+			Help: "Total number of retry attempts made against a backend",
+		}, []string{
+			"provider",
+		}),
+		metricRequestsInFlight: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "zeroex_rpc_gateway_requests_in_flight",
+			Help: "Number of requests currently being proxied to a provider",
+		}, []string{
+			"provider",
+		}),
+		metricRequestSize: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "zeroex_rpc_gateway_request_size_bytes",
+			Help:    "Histogram of request sizes forwarded to a provider",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{
+			"provider",
+		}),
+		metricResponseSize: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "zeroex_rpc_gateway_response_size_bytes",
+			Help:    "Histogram of response sizes received from a provider",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{
+			"provider",
+		}),
 	}
 
 	for _, target := range proxy.config.Targets {
@@ -80,6 +126,20 @@ func NewProxy(proxyConfig Config, healthCheckManager *HealthcheckManager) *Proxy
 		}
 	}
 
+	proxy.jsonrpcRouter = NewJSONRPCRouter(proxy.targets, proxy.healthcheckManager, proxy.dispatchWithRetry, proxy.HasNodeProviderFailed)
+
+	if len(proxyConfig.Proxy.Async.Methods) > 0 {
+		proxy.asyncMethods = make(map[string]bool, len(proxyConfig.Proxy.Async.Methods))
+		for _, method := range proxyConfig.Proxy.Async.Methods {
+			proxy.asyncMethods[method] = true
+		}
+
+		proxy.asyncBroadcaster = NewAsyncBroadcaster(proxyConfig.Proxy.Async, proxy.targets, proxy.healthcheckManager, proxy.dispatchWithRetry, proxy.HasNodeProviderFailed)
+	}
+
+	proxy.clientMetrics = prometheusmetrics.NewClientMetrics(proxyConfig.Proxy.ClientMetrics.MaxCardinality)
+	proxy.handler = middleware.ClientMetrics(proxyConfig.Proxy.ClientMetrics.APIKeyHeader, proxy.clientMetrics, http.HandlerFunc(proxy.serveHTTP))
+
 	return proxy
 }
 
@@ -92,8 +152,9 @@ func (p *Proxy) AddTarget(target TargetConfig) error {
 	p.targets = append(
 		p.targets,
 		&HTTPTarget{
-			Config: target,
-			Proxy:  proxy,
+			Config:      target,
+			Proxy:       proxy,
+			RetryPolicy: NewRetryPolicy(target.RetryPolicy),
 		})
 
 	return nil
@@ -113,50 +174,253 @@ func (p *Proxy) copyHeaders(dst http.ResponseWriter, src http.ResponseWriter) {
 	}
 }
 
-func (p *Proxy) timeoutHandler(next http.Handler) http.Handler {
+// timeoutHandler wraps next with http.TimeoutHandler(timeout), unless
+// timeout is zero or negative, in which case no upstream timeout is
+// enforced and next is returned unwrapped: http.TimeoutHandler's internal
+// ResponseWriter buffers the whole response and never exposes Flusher or
+// Hijacker, so leaving a target permanently wrapped would also
+// permanently block the streaming/upgrade support ResponseWriter's
+// delegation exists for.
+func (p *Proxy) timeoutHandler(next http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		http.TimeoutHandler(next,
-			p.config.Proxy.UpstreamTimeout,
+			timeout,
 			http.StatusText(http.StatusGatewayTimeout)).ServeHTTP(w, r)
 	}
 
 	return http.HandlerFunc(fn)
 }
 
+// timeoutFor returns the upstream timeout to use for method against
+// target: the target's per-method override if one is configured, falling
+// back to the proxy-wide default otherwise.
+func (p *Proxy) timeoutFor(target *HTTPTarget, method string) time.Duration {
+	if mc, ok := target.Config.Methods[method]; ok && mc.Timeout > 0 {
+		return mc.Timeout
+	}
+
+	return p.config.Proxy.UpstreamTimeout
+}
+
+// retryPolicyFor returns the RetryPolicy to use for method against target:
+// the target's base RetryPolicy, with MaxAttempts and the backoff delay
+// overridden by the method's config.Methods entry where set.
+func (target *HTTPTarget) retryPolicyFor(method string) RetryPolicy {
+	policy := target.RetryPolicy
+
+	if mc, ok := target.Config.Methods[method]; ok {
+		if mc.Retries > 0 {
+			policy.MaxAttempts = mc.Retries
+		}
+		if mc.Delay > 0 {
+			policy.Base = mc.Delay
+			policy.Cap = mc.Delay
+		}
+	}
+
+	return policy
+}
+
+// dispatch sends body to a single target, preserving the gzip/timeout
+// wrapping ServeHTTP has always applied, and records the per-target
+// metrics for that attempt under method. It is shared by the plain
+// failover loop and the JSONRPCRouter, which calls it once per
+// (sub-)request rather than once per whole HTTP request. w is the real
+// client ResponseWriter; the returned ResponseWriter buffers against it so
+// a failed attempt can still be retried, but transparently commits to w if
+// the target starts streaming (Flush/Hijack).
+func (p *Proxy) dispatch(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+	start := time.Now()
+
+	pw := NewResponseWriter(w)
+	req := r.Clone(r.Context())
+	req.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	var handler http.Handler = target.Proxy
+	if !target.Config.Connection.HTTP.Compression && strings.Contains(req.Header.Get(headers.ContentEncoding), "gzip") {
+		handler = middleware.Gunzip(target.Proxy)
+	}
+
+	instrumented := middleware.Instrument(target.Config.Name, p.metricRequestsInFlight, p.metricRequestSize, p.metricResponseSize, handler)
+	p.timeoutHandler(instrumented, p.timeoutFor(target, method)).ServeHTTP(withCapabilities(pw, w), req)
+
+	p.metricResponseStatus.WithLabelValues(target.Config.Name, strconv.Itoa(pw.statusCode)).Inc()
+	p.metricResponseTime.WithLabelValues(target.Config.Name, method).Observe(time.Since(start).Seconds())
+
+	if p.HasNodeProviderFailed(pw.statusCode) {
+		p.metricRequestErrors.WithLabelValues(target.Config.Name, "rerouted").Inc()
+	}
+
+	return pw
+}
+
+// dispatchWithRetry calls dispatch against target, retrying with
+// exponential backoff and full jitter per target.RetryPolicy (overridden
+// per method by target.Config.Methods, in the style of a backends.yaml
+// retries/delay override), and feeds each attempt's outcome into the
+// shared HealthcheckManager circuit breaker so live traffic and
+// healthchecks trip the same breaker. An attempt that commits (streams or
+// hijacks) is never retried even on a retryable status code, since the
+// client has already received that attempt's response.
+func (p *Proxy) dispatchWithRetry(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+	var pw *ResponseWriter
+
+	policy := target.retryPolicyFor(method)
+
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		pw = p.dispatch(target, w, r, body, method)
+
+		success := !p.HasNodeProviderFailed(pw.statusCode)
+		p.healthcheckManager.RecordRequestResult(target.Config.Name, success)
+		p.recordCircuitState(target.Config.Name)
+
+		// Once pw has committed (a streamed/flushed response, or a
+		// hijacked connection), headers and/or body have already reached
+		// the real client: it can no longer be silently retried against
+		// another target even if the status looks retryable.
+		if success || pw.committed {
+			return pw
+		}
+
+		if attempt < policy.MaxAttempts-1 {
+			p.metricBackendRetries.WithLabelValues(target.Config.Name).Inc()
+			time.Sleep(policy.Backoff(attempt))
+		}
+	}
+
+	return pw
+}
+
+func (p *Proxy) recordCircuitState(name string) {
+	breaker := p.healthcheckManager.Breaker(name)
+	if breaker == nil {
+		return
+	}
+
+	var state float64
+	switch breaker.State() {
+	case CircuitHalfOpen:
+		state = 1
+	case CircuitOpen:
+		state = 2
+	}
+
+	p.metricCircuitState.WithLabelValues(name).Set(state)
+}
+
+// wantsAsyncBroadcast reports whether req should be answered immediately
+// and broadcast in the background rather than proxied synchronously: the
+// method must be configured for async submission and, when a header is
+// configured, the client must have opted in by sending it. With no header
+// configured, every request for a configured method is handled async.
+func (p *Proxy) wantsAsyncBroadcast(r *http.Request, req *JSONRPCRequest) bool {
+	if p.asyncBroadcaster == nil || !p.asyncMethods[req.Method] {
+		return false
+	}
+
+	header := p.config.Proxy.Async.Header
+	if header == "" {
+		return true
+	}
+
+	return r.Header.Get(header) != ""
+}
+
+// serveAsync answers req with the transaction hash it will be assigned and
+// queues it for background broadcast to every healthy target. It responds
+// 503 instead if the broadcast queue is currently full.
+func (p *Proxy) serveAsync(w http.ResponseWriter, r *http.Request, req *JSONRPCRequest) {
+	hash, err := rawTransactionHash(req.Params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !p.asyncBroadcaster.Submit(r, req) {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	resp := &JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  []byte(`"` + hash + `"`),
+	}
+	body, _ := json.Marshal(resp) // nolint:errcheck
+	w.Write(body)                 // nolint:errcheck
+}
+
+// Shutdown drains any in-flight async broadcasts, bounded by
+// config.Proxy.Async.DrainTimeout when set. Callers should invoke it
+// alongside http.Server.Shutdown so pending submissions aren't abandoned
+// mid-broadcast.
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	if p.asyncBroadcaster == nil {
+		return nil
+	}
+
+	if drainTimeout := p.config.Proxy.Async.DrainTimeout; drainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, drainTimeout)
+		defer cancel()
+	}
+
+	return p.asyncBroadcaster.Shutdown(ctx)
+}
+
+// ServeHTTP records per-client metrics (see middleware.ClientMetrics) before
+// dispatching the request through serveHTTP.
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.handler.ServeHTTP(w, r)
+}
+
+func (p *Proxy) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	body := &bytes.Buffer{}
 
 	if _, err := io.Copy(body, r.Body); err != nil {
 		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
 	}
 
-	for _, target := range p.targets {
-		start := time.Now()
+	parsed := ParseJSONRPCBody(body.Bytes())
 
-		pw := NewResponseWriter()
-		r.Body = io.NopCloser(bytes.NewBuffer(body.Bytes()))
+	if parsed.Valid && !parsed.Batch && p.wantsAsyncBroadcast(r, parsed.Requests[0]) {
+		p.serveAsync(w, r, parsed.Requests[0])
+		return
+	}
+
+	if parsed.Valid && p.jsonrpcRouter.Route(w, r, parsed) {
+		return
+	}
 
-		if !target.Config.Connection.HTTP.Compression && strings.Contains(r.Header.Get(headers.ContentEncoding), "gzip") {
-			p.timeoutHandler(middleware.Gunzip(target.Proxy)).ServeHTTP(pw, r)
-		} else {
-			p.timeoutHandler(target.Proxy).ServeHTTP(pw, r)
+	// Not valid JSON-RPC (or a standalone notification): fall through to a
+	// single upstream, trying healthy targets in order, each with its retry
+	// policy, until one succeeds.
+	for _, target := range p.targets {
+		if !p.healthcheckManager.IsHealthy(target.Config.Name) {
+			continue
 		}
 
+		pw := p.dispatchWithRetry(target, w, r, body.Bytes(), r.Method)
+		if pw.committed {
+			// The target already streamed or hijacked straight through to
+			// w (e.g. a WebSocket upgrade); there's nothing left to relay.
+			return
+		}
 		if p.HasNodeProviderFailed(pw.statusCode) {
-			p.metricResponseTime.WithLabelValues(target.Config.Name, r.Method).Observe(time.Since(start).Seconds())
-			p.metricResponseStatus.WithLabelValues(target.Config.Name, strconv.Itoa(pw.statusCode)).Inc()
-			p.metricRequestErrors.WithLabelValues(target.Config.Name, "rerouted").Inc()
-
 			continue
 		}
-		p.copyHeaders(w, pw)
 
+		p.copyHeaders(w, pw)
 		w.WriteHeader(pw.statusCode)
 		w.Write(pw.body.Bytes()) // nolint:errcheck
 
-		p.metricResponseStatus.WithLabelValues(target.Config.Name, strconv.Itoa(pw.statusCode)).Inc()
-		p.metricResponseTime.WithLabelValues(target.Config.Name, r.Method).Observe(time.Since(start).Seconds())
-
 		return
 	}
 
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// roundTripperFunc lets a plain function satisfy http.RoundTripper, so a
+// target's backend can be faked without a real listener.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// newTestProxy builds a Proxy with independent (unregistered) metric
+// vectors, so dispatch/dispatchWithRetry can run without colliding with
+// promauto's default registry across tests.
+func newTestProxy(targets []*HTTPTarget) *Proxy {
+	return &Proxy{
+		// UpstreamTimeout is left at its zero value so dispatch doesn't
+		// wrap targets in http.TimeoutHandler, whose internal
+		// ResponseWriter never exposes Flusher/Hijacker; see
+		// timeoutHandler's doc comment.
+		config:             Config{},
+		targets:            targets,
+		healthcheckManager: NewHealthcheckManager(Config{}),
+		metricResponseTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "test_response_time",
+		}, []string{"provider", "method"}),
+		metricRequestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_request_errors",
+		}, []string{"provider", "type"}),
+		metricResponseStatus: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_response_status",
+		}, []string{"provider", "status_code"}),
+		metricCircuitState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "test_circuit_state",
+		}, []string{"provider"}),
+		metricBackendRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_backend_retries",
+		}, []string{"provider"}),
+		metricRequestsInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "test_requests_in_flight",
+		}, []string{"provider"}),
+		metricRequestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "test_request_size",
+		}, []string{"provider"}),
+		metricResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "test_response_size",
+		}, []string{"provider"}),
+	}
+}
+
+// TestDispatchWithRetryStopsOnceResponseHasCommitted exercises the
+// interaction the commit() doc comment warns about: a backend that streams
+// an unknown-length (Content-Length: -1) response makes ReverseProxy flush
+// immediately, which commits pw straight to the client before
+// dispatchWithRetry gets to look at the status code. Even though the
+// status is retryable, it must not try a second attempt once that's
+// happened.
+func TestDispatchWithRetryStopsOnceResponseHasCommitted(t *testing.T) {
+	attempts := 0
+	target := &HTTPTarget{
+		Config: TargetConfig{Name: "target-a"},
+		Proxy: &httputil.ReverseProxy{
+			Director: func(*http.Request) {},
+			Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				attempts++
+				return &http.Response{
+					StatusCode:    http.StatusInternalServerError,
+					Header:        http.Header{},
+					Body:          io.NopCloser(strings.NewReader("partial body")),
+					ContentLength: -1,
+				}, nil
+			}),
+		},
+		RetryPolicy: RetryPolicy{Base: time.Millisecond, Cap: time.Millisecond, MaxAttempts: 3},
+	}
+
+	p := newTestProxy([]*HTTPTarget{target})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	pw := p.dispatchWithRetry(target, w, r, nil, "eth_call")
+
+	if attempts != 1 {
+		t.Fatalf("expected exactly one attempt once the response committed, got %d", attempts)
+	}
+	if !pw.committed {
+		t.Fatalf("expected the response writer to have committed")
+	}
+	if w.Body.String() != "partial body" {
+		t.Fatalf("got body %q, want the committed attempt's body to have reached the client", w.Body.String())
+	}
+}
+
+// TestServeHTTPStopsOnceResponseHasCommitted drives the same streaming
+// scenario one layer up, through serveHTTP's non-JSON-RPC fallback loop:
+// once dispatchWithRetry's returned pw has committed, serveHTTP must not
+// call copyHeaders/WriteHeader/Write again on top of what already reached
+// the client.
+func TestServeHTTPStopsOnceResponseHasCommitted(t *testing.T) {
+	target := &HTTPTarget{
+		Config: TargetConfig{Name: "target-a"},
+		Proxy: &httputil.ReverseProxy{
+			Director: func(*http.Request) {},
+			Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode:    http.StatusOK,
+					Header:        http.Header{},
+					Body:          io.NopCloser(strings.NewReader("streamed body")),
+					ContentLength: -1,
+				}, nil
+			}),
+		},
+		RetryPolicy: RetryPolicy{Base: time.Millisecond, Cap: time.Millisecond, MaxAttempts: 3},
+	}
+
+	p := newTestProxy([]*HTTPTarget{target})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not json-rpc"))
+
+	p.serveHTTP(w, r)
+
+	if w.Body.String() != "streamed body" {
+		t.Fatalf("got body %q, want only the committed attempt's body, with nothing appended afterwards", w.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/0xProject/rpc-gateway/internal/delegate"
+)
+
+// ResponseWriter buffers a target's response so the proxy can inspect the
+// status code before deciding whether to relay it to the client or retry
+// the next target. It is a delegator in the style of
+// prometheus/client_golang/promhttp: it wraps the real client
+// http.ResponseWriter (dst) and, for the streaming interfaces dst actually
+// supports (Flush, Hijack, ReadFrom), commits the buffered response and
+// hands control straight to dst instead of buffering further; CloseNotify
+// is passed through to dst without committing. That keeps streaming subscriptions and
+// WebSocket upgrades working through the proxy even though ordinary
+// responses are still buffered for failover.
+//
+// ResponseWriter itself only implements http.ResponseWriter; Flush,
+// CloseNotify, Hijack and ReadFrom are added on top by withCapabilities,
+// which picks a wrapper exposing exactly the optional interfaces dst
+// supports. That keeps a type assertion against the wrapper (e.g.
+// `_, ok := w.(http.Hijacker)`) truthful instead of always succeeding and
+// only failing once Hijack is actually called.
+type ResponseWriter struct {
+	dst        http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	header     http.Header
+	committed  bool
+}
+
+// NewResponseWriter returns a ResponseWriter that buffers writes until
+// either the caller reads its buffered state (the normal, retryable path)
+// or a streaming operation forces it to commit directly to dst. Callers
+// that hand the result to a handler (rather than just reading its
+// buffered state back) should wrap it with withCapabilities first.
+func NewResponseWriter(dst http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{
+		dst:        dst,
+		statusCode: http.StatusOK,
+		header:     http.Header{},
+	}
+}
+
+func (w *ResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.committed {
+		return w.dst.Write(b)
+	}
+
+	return w.body.Write(b)
+}
+
+func (w *ResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+}
+
+// commit flushes the buffered status, headers and body seen so far to dst
+// and switches the writer into passthrough mode. Called the first time a
+// streaming interface is used, since once the client has seen bytes the
+// response can no longer be silently retried against another target.
+func (w *ResponseWriter) commit() {
+	if w.committed {
+		return
+	}
+
+	for k, v := range w.header {
+		w.dst.Header()[k] = v
+	}
+
+	w.dst.WriteHeader(w.statusCode)
+	w.dst.Write(w.body.Bytes()) // nolint:errcheck
+	w.body.Reset()
+
+	w.committed = true
+}
+
+func (w *ResponseWriter) flush() {
+	w.commit()
+	w.dst.(http.Flusher).Flush()
+}
+
+func (w *ResponseWriter) closeNotify() <-chan bool {
+	return w.dst.(http.CloseNotifier).CloseNotify() // nolint:staticcheck
+}
+
+func (w *ResponseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.commit()
+	return w.dst.(http.Hijacker).Hijack()
+}
+
+func (w *ResponseWriter) readFrom(src io.Reader) (int64, error) {
+	w.commit()
+	return w.dst.(io.ReaderFrom).ReadFrom(src)
+}
+
+// withCapabilities wraps pw in whichever combination of http.Flusher,
+// http.CloseNotifier, http.Hijacker and io.ReaderFrom matches what dst
+// itself implements, so a handler given the result observes pw's real
+// capabilities rather than a false positive that only surfaces as an
+// error once the optional method is actually called. The matching itself
+// is shared with middleware's sizeCountingWriter via the delegate package.
+func withCapabilities(pw *ResponseWriter, dst http.ResponseWriter) http.ResponseWriter {
+	return delegate.WithCapabilities(pw, dst, delegate.Capabilities{
+		Flush:       pw.flush,
+		CloseNotify: pw.closeNotify,
+		Hijack:      pw.hijack,
+		ReadFrom:    pw.readFrom,
+	})
+}
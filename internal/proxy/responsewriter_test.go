@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// plainResponseWriter implements only http.ResponseWriter: no Flusher,
+// CloseNotifier, Hijacker or ReaderFrom.
+type plainResponseWriter struct {
+	header http.Header
+}
+
+func (w *plainResponseWriter) Header() http.Header       { return w.header }
+func (w *plainResponseWriter) Write([]byte) (int, error) { return 0, nil }
+func (w *plainResponseWriter) WriteHeader(int)           {}
+
+// flushableResponseWriter additionally implements http.Flusher.
+type flushableResponseWriter struct {
+	plainResponseWriter
+	flushed bool
+}
+
+func (w *flushableResponseWriter) Flush() { w.flushed = true }
+
+func TestWithCapabilitiesMatchesDst(t *testing.T) {
+	plain := &plainResponseWriter{header: http.Header{}}
+	pw := NewResponseWriter(plain)
+	wrapped := withCapabilities(pw, plain)
+
+	if _, ok := wrapped.(http.Flusher); ok {
+		t.Fatalf("wrapped writer must not claim Flusher when dst doesn't implement it")
+	}
+	if _, ok := wrapped.(http.Hijacker); ok {
+		t.Fatalf("wrapped writer must not claim Hijacker when dst doesn't implement it")
+	}
+}
+
+func TestWithCapabilitiesExposesFlusher(t *testing.T) {
+	flushable := &flushableResponseWriter{plainResponseWriter: plainResponseWriter{header: http.Header{}}}
+	pw := NewResponseWriter(flushable)
+	wrapped := withCapabilities(pw, flushable)
+
+	flusher, ok := wrapped.(http.Flusher)
+	if !ok {
+		t.Fatalf("wrapped writer must expose Flusher when dst implements it")
+	}
+
+	pw.Write([]byte("hello")) // nolint:errcheck
+	flusher.Flush()
+
+	if !flushable.flushed {
+		t.Fatalf("expected Flush to commit through to dst")
+	}
+}
+
+func TestFlushCommitsBufferedResponseToDst(t *testing.T) {
+	dst := httptest.NewRecorder()
+	pw := NewResponseWriter(dst)
+	wrapped := withCapabilities(pw, dst)
+
+	pw.WriteHeader(http.StatusCreated)
+	pw.Write([]byte("hello")) // nolint:errcheck
+
+	flusher, ok := wrapped.(http.Flusher)
+	if !ok {
+		t.Fatalf("expected Flusher to be exposed for an httptest.ResponseRecorder dst")
+	}
+	flusher.Flush()
+
+	if dst.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d committed to dst before Flush", dst.Code, http.StatusCreated)
+	}
+	if dst.Body.String() != "hello" {
+		t.Fatalf("got body %q, want buffered body committed to dst before Flush", dst.Body.String())
+	}
+	if !dst.Flushed {
+		t.Fatalf("expected Flush to have been forwarded to dst")
+	}
+}
+
+func TestWithCapabilitiesExposesNeitherWhenDstSupportsNeither(t *testing.T) {
+	dst := httptest.NewRecorder()
+	// httptest.ResponseRecorder implements Flusher but neither Hijacker
+	// nor io.ReaderFrom, so this exercises the Flusher-only branch.
+	pw := NewResponseWriter(dst)
+	wrapped := withCapabilities(pw, dst)
+
+	if _, ok := wrapped.(http.Hijacker); ok {
+		t.Fatalf("wrapped writer must not claim Hijacker when dst doesn't implement it")
+	}
+	if _, ok := wrapped.(io.ReaderFrom); ok {
+		t.Fatalf("wrapped writer must not claim ReaderFrom when dst doesn't implement it")
+	}
+}
+
+// fullResponseWriter implements every optional interface withCapabilities
+// knows about, so the all-four combination is exercised too.
+type fullResponseWriter struct {
+	plainResponseWriter
+}
+
+func (w *fullResponseWriter) Flush()                                       {}
+func (w *fullResponseWriter) CloseNotify() <-chan bool                     { return make(chan bool) }
+func (w *fullResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return nil, nil, nil }
+func (w *fullResponseWriter) ReadFrom(io.Reader) (int64, error)            { return 0, nil }
+
+func TestWithCapabilitiesExposesAllFour(t *testing.T) {
+	full := &fullResponseWriter{plainResponseWriter{header: http.Header{}}}
+	pw := NewResponseWriter(full)
+	wrapped := withCapabilities(pw, full)
+
+	if _, ok := wrapped.(http.Flusher); !ok {
+		t.Fatalf("expected Flusher to be exposed")
+	}
+	if _, ok := wrapped.(http.CloseNotifier); !ok { // nolint:staticcheck
+		t.Fatalf("expected CloseNotifier to be exposed")
+	}
+	if _, ok := wrapped.(http.Hijacker); !ok {
+		t.Fatalf("expected Hijacker to be exposed")
+	}
+	if _, ok := wrapped.(io.ReaderFrom); !ok {
+		t.Fatalf("expected ReaderFrom to be exposed")
+	}
+}
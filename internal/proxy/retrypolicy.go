@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultRetryPolicy is used for any target that doesn't set its own
+// RetryPolicyConfig.
+var DefaultRetryPolicy = RetryPolicy{
+	Base:        50 * time.Millisecond,
+	Cap:         2 * time.Second,
+	MaxAttempts: 3,
+}
+
+// RetryPolicy implements exponential backoff with full jitter between
+// retries against a single target, as described in
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/.
+type RetryPolicy struct {
+	Base        time.Duration
+	Cap         time.Duration
+	MaxAttempts int
+}
+
+// NewRetryPolicy builds a RetryPolicy from config, falling back to
+// DefaultRetryPolicy for any zero field.
+func NewRetryPolicy(config RetryPolicyConfig) RetryPolicy {
+	policy := DefaultRetryPolicy
+
+	if config.Base > 0 {
+		policy.Base = config.Base
+	}
+	if config.Cap > 0 {
+		policy.Cap = config.Cap
+	}
+	if config.MaxAttempts > 0 {
+		policy.MaxAttempts = config.MaxAttempts
+	}
+
+	return policy
+}
+
+// Backoff returns how long to sleep before the given retry attempt
+// (0-indexed: attempt 0 is the delay before the first retry). It applies
+// full jitter: sleep = rand(0, min(cap, base * 2^attempt)).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	upper := p.Base << attempt
+	if upper <= 0 || upper > p.Cap {
+		upper = p.Cap
+	}
+
+	if upper <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(upper))) // nolint:gosec
+}
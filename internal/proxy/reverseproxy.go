@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// NewReverseProxy builds the httputil.ReverseProxy used to talk to a single
+// upstream target, backed by a connection pool tuned per
+// target.Connection.HTTP.Pool.
+func NewReverseProxy(target TargetConfig) (*httputil.ReverseProxy, error) {
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := NewTransport(target.Name, target.Connection.HTTP.Pool)
+	if err != nil {
+		return nil, err
+	}
+
+	reverseProxy := httputil.NewSingleHostReverseProxy(targetURL)
+	reverseProxy.Transport = transport
+
+	if n := target.Connection.HTTP.Pool.WarmupConnections; n > 0 {
+		WarmUp(&http.Client{Transport: transport}, target.URL, n)
+	}
+
+	return reverseProxy, nil
+}
@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// forwardFunc sends a single (already-framed) JSON-RPC body to one target
+// and returns the buffered response. It is supplied by Proxy so the
+// router can reuse the same timeout/gunzip wrapping as the rest of the
+// proxy without depending on Proxy directly.
+type forwardFunc func(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter
+
+// JSONRPCRouter inspects the JSON-RPC method of each (sub-)request and
+// decides how it should be dispatched to the configured targets: read-only
+// calls are tried against targets one at a time like ordinary HTTP
+// requests, while state-changing calls are broadcast to every healthy
+// target in parallel.
+type JSONRPCRouter struct {
+	targets            []*HTTPTarget
+	healthcheckManager *HealthcheckManager
+	forward            forwardFunc
+	failed             func(statusCode int) bool
+}
+
+// NewJSONRPCRouter builds a router over the given targets. failed reports
+// whether a status code should be treated as a failed upstream (the same
+// predicate Proxy uses for its own retry loop).
+func NewJSONRPCRouter(targets []*HTTPTarget, healthcheckManager *HealthcheckManager, forward forwardFunc, failed func(int) bool) *JSONRPCRouter {
+	return &JSONRPCRouter{
+		targets:            targets,
+		healthcheckManager: healthcheckManager,
+		forward:            forward,
+		failed:             failed,
+	}
+}
+
+// Route dispatches a parsed JSON-RPC body and writes the reassembled
+// response to w. It returns false if the body wasn't valid JSON-RPC, or is
+// a standalone notification, in which case the caller should fall back to
+// forwarding it unchanged: a notification has no id and expects no reply,
+// so there's nothing for Route to reassemble a response from.
+func (router *JSONRPCRouter) Route(w http.ResponseWriter, r *http.Request, parsed *ParsedBody) bool {
+	if !parsed.Valid {
+		return false
+	}
+
+	if !parsed.Batch && parsed.Requests[0].IsNotification() {
+		return false
+	}
+
+	responses := make([]*JSONRPCResponse, len(parsed.Requests))
+	for i, req := range parsed.Requests {
+		if req.IsStateChanging() {
+			responses[i] = router.broadcast(r, req)
+			continue
+		}
+
+		resp, committed := router.routeOne(w, r, req)
+		if committed {
+			// The winning attempt already streamed or hijacked straight
+			// through to w; the response has already been sent and
+			// nothing more may be written, even for the other requests
+			// in this batch.
+			return true
+		}
+
+		responses[i] = resp
+	}
+
+	if !parsed.Batch {
+		if responses[0] == nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return true
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := json.Marshal(responses[0]) // nolint:errcheck
+		w.Write(body)                         // nolint:errcheck
+
+		return true
+	}
+
+	body, err := MarshalBatchResponse(parsed.Requests, responses)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body) // nolint:errcheck
+
+	return true
+}
+
+// routeOne tries targets in configured order until one returns a
+// non-failed response, mirroring Proxy's own retry loop but scoped to a
+// single JSON-RPC request. The returned bool reports whether the winning
+// attempt had already committed (streamed or hijacked) straight to w: when
+// it has, pw.body was reset by commit() and is no longer the real response,
+// so the caller must not try to decode or write anything further.
+func (router *JSONRPCRouter) routeOne(w http.ResponseWriter, r *http.Request, req *JSONRPCRequest) (resp *JSONRPCResponse, committed bool) {
+	for _, target := range router.targets {
+		if !router.healthcheckManager.IsHealthy(target.Config.Name) {
+			continue
+		}
+
+		pw := router.forward(target, w, r, req.raw, req.Method)
+		if pw.committed {
+			return nil, true
+		}
+		if router.failed(pw.statusCode) {
+			continue
+		}
+
+		return decodeJSONRPCResponse(pw.body.Bytes(), req.ID), false
+	}
+
+	return nil, false
+}
+
+// broadcast fans a state-changing request out to every healthy target in
+// parallel and returns the first successful response to arrive; the other
+// targets' responses are discarded without being compared, so the caller
+// only sees one. Each goroutine forwards against a
+// noopResponseWriter rather than the real client w: w is shared across all
+// of them, and ResponseWriter commits straight to it the moment a target
+// starts streaming (Flush/Hijack), which would race with Route's own
+// aggregated write of the first successful response. Route is the only
+// thing that writes to w for a broadcast request.
+func (router *JSONRPCRouter) broadcast(r *http.Request, req *JSONRPCRequest) *JSONRPCResponse {
+	type result struct {
+		resp *JSONRPCResponse
+		ok   bool
+	}
+
+	results := make(chan result, len(router.targets))
+	inflight := 0
+
+	for _, target := range router.targets {
+		if !router.healthcheckManager.IsHealthy(target.Config.Name) {
+			continue
+		}
+
+		inflight++
+
+		go func(target *HTTPTarget) {
+			pw := router.forward(target, noopResponseWriter{}, r, req.raw, req.Method)
+			results <- result{
+				resp: decodeJSONRPCResponse(pw.body.Bytes(), req.ID),
+				ok:   !router.failed(pw.statusCode),
+			}
+		}(target)
+	}
+
+	var first *JSONRPCResponse
+	for i := 0; i < inflight; i++ {
+		res := <-results
+		if res.ok && first == nil {
+			first = res.resp
+		}
+	}
+
+	return first
+}
+
+func decodeJSONRPCResponse(body []byte, id json.RawMessage) *JSONRPCResponse {
+	resp := &JSONRPCResponse{}
+	if err := json.Unmarshal(bytes.TrimSpace(body), resp); err != nil {
+		return nil
+	}
+
+	resp.ID = id
+
+	return resp
+}
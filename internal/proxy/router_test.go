@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteReturnsFalseForStandaloneNotification(t *testing.T) {
+	forward := func(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+		t.Fatalf("a standalone notification must fall through to the caller instead of being dispatched by Route")
+		return nil
+	}
+
+	targets := []*HTTPTarget{{Config: TargetConfig{Name: "target-a"}}}
+	healthcheckManager := NewHealthcheckManager(Config{Targets: []TargetConfig{targets[0].Config}})
+	router := NewJSONRPCRouter(targets, healthcheckManager, forward, func(int) bool { return false })
+
+	parsed := ParseJSONRPCBody([]byte(`{"jsonrpc":"2.0","method":"eth_subscribe"}`))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	if router.Route(w, r, parsed) {
+		t.Fatalf("Route must return false for a standalone notification so the caller forwards it unchanged")
+	}
+}
+
+func TestRouteHandlesOrdinaryRequest(t *testing.T) {
+	forward := func(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+		pw := NewResponseWriter(w)
+		pw.body.WriteString(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+		return pw
+	}
+
+	targets := []*HTTPTarget{{Config: TargetConfig{Name: "target-a"}}}
+	healthcheckManager := NewHealthcheckManager(Config{Targets: []TargetConfig{targets[0].Config}})
+	router := NewJSONRPCRouter(targets, healthcheckManager, forward, func(int) bool { return false })
+
+	parsed := ParseJSONRPCBody([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	if !router.Route(w, r, parsed) {
+		t.Fatalf("Route must handle a plain request with an id")
+	}
+	if w.Body.Len() == 0 {
+		t.Fatalf("expected Route to write a response body")
+	}
+}
+
+// TestRouteStopsOnceResponseHasCommitted exercises the same hazard
+// TestDispatchWithRetryStopsOnceResponseHasCommitted covers for
+// dispatchWithRetry, one layer up: once the real ResponseWriter has
+// committed (streamed) straight to w, routeOne's pw.body no longer holds
+// the real response, so Route must not try to decode it, write a
+// reassembled response, or fall back to a 503 on top of what's already
+// been sent to the client.
+func TestRouteStopsOnceResponseHasCommitted(t *testing.T) {
+	forward := func(target *HTTPTarget, w http.ResponseWriter, r *http.Request, body []byte, method string) *ResponseWriter {
+		pw := NewResponseWriter(w)
+		wrapped := withCapabilities(pw, w)
+		wrapped.(http.Flusher).Flush() // commits pw straight to w, as a streamed (ContentLength: -1) upstream would
+		return pw
+	}
+
+	targets := []*HTTPTarget{{Config: TargetConfig{Name: "target-a"}}}
+	healthcheckManager := NewHealthcheckManager(Config{Targets: []TargetConfig{targets[0].Config}})
+	router := NewJSONRPCRouter(targets, healthcheckManager, forward, func(int) bool { return false })
+
+	parsed := ParseJSONRPCBody([]byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	if !router.Route(w, r, parsed) {
+		t.Fatalf("Route must report the request as handled once a target has committed")
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want the committed attempt's original status, not an overwritten 503", w.Code)
+	}
+}
@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// defaultDialTimeout is used when a target doesn't configure PoolConfig.DialTimeout.
+const defaultDialTimeout = 5 * time.Second
+
+// poolMetrics are shared across every target's transport, labeled by
+// provider.
+type poolMetrics struct {
+	idleConns   *prometheus.GaugeVec
+	activeConns *prometheus.GaugeVec
+	dialErrors  *prometheus.CounterVec
+}
+
+var pool = poolMetrics{
+	idleConns: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "zeroex_rpc_gateway_pool_idle_conns",
+		Help: "Number of idle connections currently held open to a provider",
+	}, []string{"provider"}),
+	activeConns: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "zeroex_rpc_gateway_pool_active_conns",
+		Help: "Number of open connections (idle or in use) to a provider",
+	}, []string{"provider"}),
+	dialErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "zeroex_rpc_gateway_pool_dial_errors_total",
+		Help: "Total number of failed dial attempts to a provider",
+	}, []string{"provider"}),
+}
+
+// NewTransport builds an http.RoundTripper for a target with a connection
+// pool tuned per config, instrumented with the pool_* metrics above.
+func NewTransport(provider string, config PoolConfig) (http.RoundTripper, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	dialTimeout := config.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = defaultDialTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	transport := &http.Transport{
+		DialContext:           trackedDialContext(provider, dialer.DialContext),
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ExpectContinueTimeout: config.ExpectContinueTimeout,
+	}
+
+	if config.DisableHTTP2 {
+		// An empty, non-nil TLSNextProto stops the transport from
+		// upgrading to HTTP/2 over TLS.
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if config.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: loading client certificate: %w", err)
+		}
+
+		transport.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}} // nolint:gosec
+	}
+
+	return &instrumentedTransport{provider: provider, next: transport}, nil
+}
+
+// trackedDialContext wraps dial so dial failures and the number of open
+// connections are reflected in the pool metrics.
+func trackedDialContext(provider string, dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			pool.dialErrors.WithLabelValues(provider).Inc()
+			return nil, err
+		}
+
+		pool.activeConns.WithLabelValues(provider).Inc()
+
+		return &trackedConn{Conn: conn, provider: provider}, nil
+	}
+}
+
+// trackedConn decrements the active connection gauge exactly once when
+// the underlying connection is closed, however that happens (request
+// completion, idle timeout eviction, or explicit close).
+type trackedConn struct {
+	net.Conn
+	provider string
+	closed   bool
+}
+
+func (c *trackedConn) Close() error {
+	if !c.closed {
+		c.closed = true
+		pool.activeConns.WithLabelValues(c.provider).Dec()
+	}
+
+	return c.Conn.Close()
+}
+
+// instrumentedTransport wraps an http.RoundTripper with an httptrace hook
+// that tracks how many of a provider's connections are currently idle in
+// the pool versus checked out for a request.
+type instrumentedTransport struct {
+	provider string
+	next     http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.WasIdle {
+				pool.idleConns.WithLabelValues(t.provider).Dec()
+			}
+		},
+		PutIdleConn: func(err error) {
+			if err == nil {
+				pool.idleConns.WithLabelValues(t.provider).Inc()
+			}
+		},
+	}
+
+	return t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// WarmUp pre-dials n connections to target by firing lightweight
+// requests through client, so the pool already has warm connections
+// before the first real request arrives.
+func WarmUp(client *http.Client, targetURL string, n int) {
+	for i := 0; i < n; i++ {
+		go func() {
+			req, err := http.NewRequest(http.MethodHead, targetURL, nil)
+			if err != nil {
+				return
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return
+			}
+
+			resp.Body.Close() // nolint:errcheck
+		}()
+	}
+}
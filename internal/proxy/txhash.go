@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// rawTransactionHash returns the transaction hash the network will assign
+// to a raw signed transaction, without having to wait for any upstream to
+// accept it: for both legacy and typed transactions this is simply
+// keccak256 of the raw signed bytes.
+func rawTransactionHash(params json.RawMessage) (string, error) {
+	var args []string
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 {
+		return "", fmt.Errorf("proxy: eth_sendRawTransaction expects a single raw tx param")
+	}
+
+	raw := strings.TrimPrefix(args[0], "0x")
+
+	data, err := hex.DecodeString(raw)
+	if err != nil {
+		return "", fmt.Errorf("proxy: invalid raw transaction hex: %w", err)
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(data) // nolint:errcheck
+
+	return "0x" + hex.EncodeToString(hash.Sum(nil)), nil
+}